@@ -0,0 +1,102 @@
+// Package configs holds the configuration types gnode is driven by, parsed
+// from ini files by internal/gnode and kept here so it has no dependency on
+// any particular subsystem.
+package configs
+
+// GnodeConfig is the fully parsed, defaulted configuration for a single
+// gnode instance.
+type GnodeConfig struct {
+	NodeId int64
+
+	// shutdown_timeout (seconds) bounds how long Shutdown waits for
+	// connections to drain before it gives up and closes anyway; <= 0 means
+	// use defaultShutdownTimeout.
+	ShutdownTimeout int
+
+	// log
+	LogFilename   string
+	LogLevel      int
+	LogRotate     bool
+	LogMaxSize    int
+	LogFormat     string // "json" (default) or "console"
+	LogSampling   int    // log 1 in every N messages; 0 disables sampling
+	LogTargetType string
+
+	// redis
+	RedisHost                  string
+	RedisPwd                   string
+	RedisPort                  string
+	RedisMaxIdle               int
+	RedisMaxActive             int
+	RedisMode                  string // "single" (default), "sentinel" or "cluster"
+	RedisAddrs                 []string
+	RedisSentinelMaster        string
+	RedisTlsEnable             bool
+	RedisTlsInsecureSkipVerify bool
+
+	// bucket
+	BucketNum    int
+	TTRBucketNum int
+
+	// http server
+	HttpServAddr      string
+	HttpServCertFile  string
+	HttpServKeyFile   string
+	HttpServEnableTls bool
+
+	// tcp server
+	TcpServAddr      string
+	TcpServCertFile  string
+	TcpServKeyFile   string
+	TcpServEnableTls bool
+	TcpServWeight    int
+
+	// gregister (legacy http-only register)
+	GregisterAddr string
+
+	// registry
+	RegistryBackend string
+	RegistryAddrs   string
+
+	// metrics
+	MetricsEnabled bool
+
+	// tracing
+	TracingEnabled     bool
+	TracingEndpoint    string
+	TracingServiceName string
+	TracingSampleRatio float64
+}
+
+// SetDefault fills in zero-valued fields with gnode's defaults. It must be
+// called once after parsing, and again on every Reload so a partially
+// specified ini section doesn't lose its defaults.
+func (cfg *GnodeConfig) SetDefault() {
+	if cfg.LogTargetType == "" {
+		cfg.LogTargetType = "console"
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "json"
+	}
+	if cfg.BucketNum <= 0 {
+		cfg.BucketNum = 10
+	}
+	if cfg.TTRBucketNum <= 0 {
+		cfg.TTRBucketNum = 10
+	}
+	if cfg.RedisMaxIdle <= 0 {
+		cfg.RedisMaxIdle = 10
+	}
+	if cfg.RedisMaxActive <= 0 {
+		cfg.RedisMaxActive = 100
+	}
+	if cfg.TcpServWeight <= 0 {
+		cfg.TcpServWeight = 1
+	}
+	if cfg.RegistryBackend == "" {
+		cfg.RegistryBackend = "http"
+	}
+	if cfg.RedisMode == "" {
+		cfg.RedisMode = "single"
+	}
+}
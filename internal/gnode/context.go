@@ -0,0 +1,17 @@
+package gnode
+
+import (
+	"github.com/wuzhc/gmq/configs"
+	"github.com/wuzhc/gmq/pkg/logger"
+	"github.com/wuzhc/gmq/pkg/metrics"
+)
+
+// Context bundles the dependencies every gnode subsystem (dispatcher, tcp,
+// http) is constructed with, so NewXxx(ctx) only ever needs one argument.
+type Context struct {
+	Gnode   *Gnode
+	Conf    *configs.GnodeConfig
+	Logger  *logger.Logger
+	RedisDB *RedisDB
+	Metrics *metrics.Registry
+}
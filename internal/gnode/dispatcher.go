@@ -0,0 +1,63 @@
+package gnode
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// bucketScanInterval controls how often Dispatcher reads the bucket hashes
+// from Redis. A scan only reads them and records its latency; it moves no
+// message onto a topic's ready queue.
+const bucketScanInterval = time.Second
+
+// Dispatcher runs the bucket-scan loop. It is the one subsystem that calls
+// RedisDB.ScanBuckets.
+type Dispatcher struct {
+	ctx  *Context
+	stop chan struct{}
+}
+
+func NewDispatcher(ctx *Context) *Dispatcher {
+	return &Dispatcher{ctx: ctx, stop: make(chan struct{})}
+}
+
+// Run scans buckets on a fixed interval until Stop closes d.stop.
+func (d *Dispatcher) Run() {
+	ticker := time.NewTicker(bucketScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.scan()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) scan() {
+	cfg := d.ctx.Conf
+	keys := make([]string, 0, cfg.BucketNum)
+	for i := 0; i < cfg.BucketNum; i++ {
+		keys = append(keys, fmt.Sprintf("gmq:bucket:%d", i))
+	}
+
+	start := time.Now()
+	_, err := d.ctx.RedisDB.ScanBuckets(d.ctx.Gnode.ctx, keys)
+	if d.ctx.Metrics != nil {
+		d.ctx.Metrics.BucketScanDuration.WithLabelValues("default").Observe(time.Since(start).Seconds())
+	}
+	if err != nil {
+		d.ctx.Logger.Error("bucket scan failed", err)
+	}
+}
+
+// Stop signals the scan loop to exit. The passed ctx is accepted only so
+// Dispatcher satisfies the subsystem interface Shutdown drains uniformly;
+// the loop has no in-flight work to wait on.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.stop)
+	return nil
+}
@@ -0,0 +1,56 @@
+package gnode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// HttpServ exposes gnode's admin HTTP surface: /reload for the hot-reload
+// trigger HandleReload implements, plus /metrics when [metrics] enabled=true.
+type HttpServ struct {
+	ctx    *Context
+	server *http.Server
+}
+
+func NewHttpServ(ctx *Context) *HttpServ {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reload", ctx.Gnode.HandleReload)
+	if h := ctx.Gnode.MetricsHandler(); h != nil {
+		mux.Handle("/metrics", h)
+	}
+
+	return &HttpServ{
+		ctx: ctx,
+		server: &http.Server{
+			Addr:    ctx.Conf.HttpServAddr,
+			Handler: mux,
+		},
+	}
+}
+
+// Run serves until Stop calls server.Shutdown, at which point
+// ListenAndServe[TLS] returns http.ErrServerClosed, which isn't logged since
+// it's the expected outcome of a graceful stop.
+func (h *HttpServ) Run() {
+	cfg := h.ctx.Conf
+
+	var err error
+	if cfg.HttpServEnableTls {
+		err = h.server.ListenAndServeTLS(cfg.HttpServCertFile, cfg.HttpServKeyFile)
+	} else {
+		err = h.server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		h.ctx.Logger.Error("http serv stopped", err)
+	}
+}
+
+// Stop drains in-flight requests until ctx's deadline, same contract as
+// net/http.Server.Shutdown.
+func (h *HttpServ) Stop(ctx context.Context) error {
+	if err := h.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("http serv: shutdown, %w", err)
+	}
+	return nil
+}
@@ -2,99 +2,213 @@ package gnode
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/wuzhc/gmq/configs"
-	"github.com/wuzhc/gmq/pkg/logs"
+	"github.com/wuzhc/gmq/internal/gnode/registry"
+	"github.com/wuzhc/gmq/pkg/logger"
+	"github.com/wuzhc/gmq/pkg/metrics"
+	"github.com/wuzhc/gmq/pkg/tracing"
 	"github.com/wuzhc/gmq/pkg/utils"
 
 	"gopkg.in/ini.v1"
 )
 
+// renewInterval controls how often the registrar's lease/session is
+// refreshed; it must stay comfortably under the backend's TTL.
+const renewInterval = 5 * time.Second
+
 type Gnode struct {
-	running  int32
-	exitChan chan struct{}
-	ctx      context.Context
-	wg       utils.WaitGroupWrapper
-	cfg      *configs.GnodeConfig
+	running        int32
+	exitChan       chan struct{}
+	ctx            context.Context
+	cancel         context.CancelFunc // cancels ctx; called from Shutdown alongside closing exitChan
+	wg             utils.WaitGroupWrapper
+	cfg            atomic.Value // stores *configs.GnodeConfig
+	cfgFile        string
+	reloadMu       sync.Mutex // serializes Reload against SIGHUP/fsnotify/HTTP triggering it concurrently
+	logger         *logger.Logger
+	metrics        *metrics.Registry
+	tracerShutdown tracing.Shutdown
+	registrar      registry.Registrar
+	dispatcher     subsystem
+	httpServ       subsystem
+	tcpServ        subsystem
+	redisDB        *RedisDB
+	peers          *peerList
+}
+
+// subsystem is implemented by the dispatcher, tcp and http servers so
+// Shutdown can drain them without depending on their concrete types.
+type subsystem interface {
+	Run()
+	Stop(ctx context.Context) error
 }
 
 func New() *Gnode {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Gnode{
-		ctx:      context.Background(),
+		ctx:      ctx,
+		cancel:   cancel,
 		exitChan: make(chan struct{}),
+		peers:    newPeerList(),
 	}
 }
 
-func (gn *Gnode) Run() {
+// Config returns the currently active configuration. It is safe to call
+// concurrently with Reload swapping in a new one.
+func (gn *Gnode) Config() *configs.GnodeConfig {
+	cfg, _ := gn.cfg.Load().(*configs.GnodeConfig)
+	return cfg
+}
+
+// Run starts every gnode subsystem and blocks until Exit/Shutdown is called.
+// Startup failures are returned rather than fatal'd out so embedding
+// programs can catch and react to them instead of having the process killed
+// out from under them.
+func (gn *Gnode) Run() error {
 	defer gn.wg.Wait()
 
 	if atomic.LoadInt32(&gn.running) == 1 {
-		log.Fatalln("Gnode is running.")
+		return fmt.Errorf("gnode is already running")
 	}
 	if !atomic.CompareAndSwapInt32(&gn.running, 0, 1) {
-		log.Fatalln("Gnode start failed.")
+		return fmt.Errorf("gnode start failed")
+	}
+
+	if gn.Config() == nil {
+		if err := gn.SetDefaultConfig(); err != nil {
+			return err
+		}
+	}
+
+	rootLogger, err := gn.initLogger()
+	if err != nil {
+		return fmt.Errorf("init logger failed, %w", err)
 	}
+	gn.logger = rootLogger
 
-	if gn.cfg == nil {
-		gn.SetDefaultConfig()
+	cfg := gn.Config()
+	if cfg.MetricsEnabled {
+		gn.metrics = metrics.New(prometheus.DefaultRegisterer)
 	}
 
+	redisDB, err := gn.initRedisPool()
+	if err != nil {
+		return fmt.Errorf("init redis failed, %w", err)
+	}
+	gn.redisDB = redisDB
+
+	tracerShutdown, err := tracing.Init(gn.ctx, tracing.Config{
+		Enabled:     cfg.TracingEnabled,
+		Endpoint:    cfg.TracingEndpoint,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("init tracing failed, %w", err)
+	}
+	gn.tracerShutdown = tracerShutdown
+
 	ctx := &Context{
 		Gnode:   gn,
-		Conf:    gn.cfg,
-		Logger:  gn.initLogger(),
-		RedisDB: gn.initRedisPool(),
+		Conf:    cfg,
+		Logger:  rootLogger,
+		RedisDB: redisDB,
+		Metrics: gn.metrics,
 	}
 
-	gn.wg.Wrap(NewDispatcher(ctx).Run)
-	gn.wg.Wrap(NewHttpServ(ctx).Run)
-	gn.wg.Wrap(NewTcpServ(ctx).Run)
+	// Registry setup happens before any subsystem goroutine is wrapped, so the
+	// deferred gn.wg.Wait above returns at once when it fails.
+	registrar, err := registry.New(gn.Config())
+	if err != nil {
+		return fmt.Errorf("registry init failed, %w", err)
+	}
+	gn.registrar = registrar
 
 	if err := gn.register(); err != nil {
-		log.Fatalln("Register failed, ", err)
+		return fmt.Errorf("register failed, %w", err)
+	}
+
+	gn.dispatcher = NewDispatcher(ctx)
+	gn.httpServ = NewHttpServ(ctx)
+	gn.tcpServ = NewTcpServ(ctx)
+
+	gn.wg.Wrap(gn.dispatcher.Run)
+	gn.wg.Wrap(gn.httpServ.Run)
+	gn.wg.Wrap(gn.tcpServ.Run)
+	gn.wg.Wrap(gn.renewRegistration)
+	gn.wg.Wrap(gn.watchRegistry)
+
+	if gn.cfgFile != "" {
+		gn.wg.Wrap(gn.watchConfig)
 	}
 
 	gn.installSignalHandler()
 	ctx.Logger.Info("Gnode is running.")
+	return nil
 }
 
-func (gn *Gnode) Exit() {
-	if err := gn.unregister(); err != nil {
-		log.Fatalln("failed")
+// MetricsHandler is registered by HttpServ at GET /metrics when
+// [metrics] enabled=true; it is nil otherwise so the route isn't mounted.
+func (gn *Gnode) MetricsHandler() http.Handler {
+	if gn.metrics == nil {
+		return nil
+	}
+	return metrics.Handler()
+}
+
+func (gn *Gnode) SetConfig(cfgFile string) error {
+	cfg, err := parseConfigFile(cfgFile)
+	if err != nil {
+		return err
 	}
 
-	close(gn.exitChan)
+	// CLI flags only make sense at startup, so they're layered on top of the
+	// ini values here rather than inside parseConfigFile, which Reload also
+	// calls.
+	flag.StringVar(&cfg.TcpServAddr, "tcp_addr", cfg.TcpServAddr, "tcp address")
+	flag.StringVar(&cfg.HttpServAddr, "http_addr", cfg.HttpServAddr, "http address")
+	flag.Parse()
+
+	cfg.SetDefault()
+	gn.cfg.Store(cfg)
+	gn.cfgFile = cfgFile
+	return nil
 }
 
-func (gn *Gnode) SetConfig(cfgFile string) {
+// parseConfigFile reads cfgFile into a fresh GnodeConfig. It is used both at
+// startup (SetConfig) and on hot-reload (Reload), which is why tcp/http
+// listen addresses are parsed from ini here but CLI flags, which only make
+// sense at startup, are applied by SetConfig itself afterwards.
+func parseConfigFile(cfgFile string) (*configs.GnodeConfig, error) {
 	if res, err := utils.PathExists(cfgFile); !res {
 		if err != nil {
-			log.Fatalf("%s is not exists,errors:%s \n", cfgFile, err.Error())
-		} else {
-			log.Fatalf("%s is not exists \n", cfgFile)
+			return nil, fmt.Errorf("%s is not exists, errors:%w", cfgFile, err)
 		}
+		return nil, fmt.Errorf("%s is not exists", cfgFile)
 	}
 
 	c, err := ini.Load(cfgFile)
 	if err != nil {
-		log.Fatalf("Fail to read file: %v \n", err)
+		return nil, fmt.Errorf("fail to read file: %w", err)
 	}
 
 	cfg := new(configs.GnodeConfig)
 
 	// node
 	cfg.NodeId, _ = c.Section("node").Key("id").Int64()
+	cfg.ShutdownTimeout, _ = c.Section("node").Key("shutdown_timeout").Int()
 
 	// log config
 	cfg.LogFilename = c.Section("log").Key("filename").String()
@@ -102,6 +216,8 @@ func (gn *Gnode) SetConfig(cfgFile string) {
 	cfg.LogRotate, _ = c.Section("log").Key("rotate").Bool()
 	cfg.LogMaxSize, _ = c.Section("log").Key("max_size").Int()
 	cfg.LogTargetType = c.Section("log").Key("target_type").String()
+	cfg.LogFormat = c.Section("log").Key("format").String()
+	cfg.LogSampling, _ = c.Section("log").Key("sampling").Int()
 
 	// redis config
 	cfg.RedisHost = c.Section("redis").Key("host").String()
@@ -109,6 +225,11 @@ func (gn *Gnode) SetConfig(cfgFile string) {
 	cfg.RedisPort = c.Section("redis").Key("port").String()
 	cfg.RedisMaxIdle, _ = c.Section("redis").Key("max_idle").Int()
 	cfg.RedisMaxActive, _ = c.Section("redis").Key("max_active").Int()
+	cfg.RedisMode = c.Section("redis").Key("mode").String()
+	cfg.RedisAddrs = c.Section("redis").Key("addrs").Strings(",")
+	cfg.RedisSentinelMaster = c.Section("redis").Key("sentinel_master").String()
+	cfg.RedisTlsEnable, _ = c.Section("redis").Key("tls_enable").Bool()
+	cfg.RedisTlsInsecureSkipVerify, _ = c.Section("redis").Key("tls_insecure_skip_verify").Bool()
 
 	// bucket config
 	cfg.BucketNum, _ = c.Section("bucket").Key("num").Int()
@@ -130,24 +251,35 @@ func (gn *Gnode) SetConfig(cfgFile string) {
 	// register config
 	cfg.GregisterAddr = c.Section("gregister").Key("addr").String()
 
-	// parse flag
-	flag.StringVar(&cfg.TcpServAddr, "tcp_addr", tcpServAddr, "tcp address")
-	flag.StringVar(&cfg.HttpServAddr, "http_addr", httpServAddr, "http address")
-	flag.Parse()
+	// registry config
+	cfg.RegistryBackend = c.Section("registry").Key("backend").String()
+	cfg.RegistryAddrs = c.Section("registry").Key("addrs").String()
+
+	// metrics config
+	cfg.MetricsEnabled, _ = c.Section("metrics").Key("enabled").Bool()
+
+	// tracing config
+	cfg.TracingEnabled, _ = c.Section("tracing").Key("enabled").Bool()
+	cfg.TracingEndpoint = c.Section("tracing").Key("endpoint").String()
+	cfg.TracingServiceName = c.Section("tracing").Key("service_name").String()
+	cfg.TracingSampleRatio, _ = c.Section("tracing").Key("sample_ratio").Float64()
+
+	cfg.TcpServAddr = tcpServAddr
+	cfg.HttpServAddr = httpServAddr
 
-	gn.cfg = cfg
-	gn.cfg.SetDefault()
+	return cfg, nil
 }
 
-func (gn *Gnode) SetDefaultConfig() {
+func (gn *Gnode) SetDefaultConfig() error {
 	cfg := new(configs.GnodeConfig)
 
-	flag.StringVar(&gn.cfg.TcpServAddr, "tcp_addr", "", "tcp address")
-	flag.StringVar(&gn.cfg.HttpServAddr, "http_addr", "", "http address")
+	flag.StringVar(&cfg.TcpServAddr, "tcp_addr", "", "tcp address")
+	flag.StringVar(&cfg.HttpServAddr, "http_addr", "", "http address")
 	flag.Parse()
 
-	gn.cfg = cfg
-	gn.cfg.SetDefault()
+	cfg.SetDefault()
+	gn.cfg.Store(cfg)
+	return nil
 }
 
 func (gn *Gnode) installSignalHandler() {
@@ -155,83 +287,71 @@ func (gn *Gnode) installSignalHandler() {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		<-sigs
-		gn.Exit()
+		for sig := range sigs {
+			if sig == syscall.SIGHUP {
+				if err := gn.Reload(); err != nil {
+					log.Println("Reload failed, ", err)
+				}
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), gn.shutdownTimeout())
+			if err := gn.Shutdown(ctx); err != nil {
+				log.Println("Shutdown failed, ", err)
+			}
+			cancel()
+			return
+		}
 	}()
 }
 
-func (gn *Gnode) initLogger() *logs.Dispatcher {
-	logger := logs.NewDispatcher()
-	targets := strings.Split(gn.cfg.LogTargetType, ",")
-	for _, t := range targets {
-		if t == logs.TARGET_FILE {
-			conf := fmt.Sprintf(`{"filename":"%s","level":%d,"max_size":%d,"rotate":%v}`, gn.cfg.LogFilename, gn.cfg.LogLevel, gn.cfg.LogMaxSize, gn.cfg.LogRotate)
-			logger.SetTarget(logs.TARGET_FILE, conf)
-		} else if t == logs.TARGET_CONSOLE {
-			logger.SetTarget(logs.TARGET_CONSOLE, "")
-		} else {
-			log.Fatalln("Only support file or console handler")
-		}
-	}
-	return logger
+func (gn *Gnode) initLogger() (*logger.Logger, error) {
+	cfg := gn.Config()
+	return logger.New(logger.Config{
+		NodeId:     cfg.NodeId,
+		Filename:   cfg.LogFilename,
+		Level:      cfg.LogLevel,
+		Rotate:     cfg.LogRotate,
+		MaxSize:    cfg.LogMaxSize,
+		Format:     cfg.LogFormat,
+		Sampling:   cfg.LogSampling,
+		TargetType: cfg.LogTargetType,
+	})
 }
 
-func (gn *Gnode) initRedisPool() *RedisDB {
-	return Redis.InitPool(gn.cfg)
+func (gn *Gnode) initRedisPool() (*RedisDB, error) {
+	return NewRedisDB(gn.ctx, gn.Config(), gn.metrics)
 }
 
-type rs struct {
-	Code int         `json:"code"`
-	Data interface{} `json:"data"`
-	Msg  string      `json:"msg"`
+func (gn *Gnode) nodeInfo() registry.NodeInfo {
+	cfg := gn.Config()
+	return registry.NodeInfo{
+		NodeId:   cfg.NodeId,
+		TcpAddr:  cfg.TcpServAddr,
+		HttpAddr: cfg.HttpServAddr,
+		Weight:   cfg.TcpServWeight,
+	}
 }
 
 func (gn *Gnode) register() error {
-	hosts := strings.Split(gn.cfg.GregisterAddr, ",")
-	for _, host := range hosts {
-		url := fmt.Sprintf("%s/register?tcp_addr=%s&http_addr=%s&weight=%d&node_id=%d", host, gn.cfg.TcpServAddr, gn.cfg.HttpServAddr, gn.cfg.TcpServWeight, gn.cfg.NodeId)
-		resp, err := http.Get(url)
-		if err != nil {
-			return err
-		}
-		res, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		var r rs
-		if err := json.Unmarshal(res, &r); err != nil {
-			log.Fatalln(err)
-		}
-		if r.Code == 1 {
-			log.Fatalln(r.Msg)
-		}
-	}
-
-	return nil
+	return gn.registrar.Register(gn.ctx, gn.nodeInfo())
 }
 
-func (gn *Gnode) unregister() error {
-	ts := strings.Split(gn.cfg.GregisterAddr, ",")
-	for _, t := range ts {
-		url := t + "/unregister?tcp_addr=" + gn.cfg.TcpServAddr
-		resp, err := http.Get(url)
-		if err != nil {
-			return err
-		}
-		res, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
-
-		var r rs
-		if err := json.Unmarshal(res, &r); err != nil {
-			log.Fatalln(err)
-		}
-		if r.Code == 1 {
-			log.Fatalln(r.Msg)
+// renewRegistration keeps the registrar's lease/session alive for as long as
+// Gnode runs, so a node that dies without calling Exit() expires on its own
+// instead of leaving a stale route behind.
+func (gn *Gnode) renewRegistration() {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := gn.registrar.Renew(gn.ctx); err != nil {
+				log.Println("Renew registration failed, ", err)
+			}
+		case <-gn.exitChan:
+			return
 		}
 	}
-
-	return nil
 }
\ No newline at end of file
@@ -0,0 +1,70 @@
+package gnode
+
+import (
+	"sync"
+
+	"github.com/wuzhc/gmq/internal/gnode/registry"
+)
+
+// peerList is the live view of every other gnode node, kept up to date by
+// watchRegistry instead of the dispatcher/producers polling gregister.
+type peerList struct {
+	mu    sync.RWMutex
+	nodes map[int64]registry.NodeInfo
+}
+
+func newPeerList() *peerList {
+	return &peerList{nodes: make(map[int64]registry.NodeInfo)}
+}
+
+func (p *peerList) apply(ev registry.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ev.Type {
+	case registry.EventAdd:
+		p.nodes[ev.Node.NodeId] = ev.Node
+	case registry.EventRemove:
+		delete(p.nodes, ev.Node.NodeId)
+	}
+}
+
+// Snapshot returns the currently known peers; Gnode.Peers exposes it.
+func (p *peerList) Snapshot() []registry.NodeInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make([]registry.NodeInfo, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		out = append(out, n)
+	}
+	return out
+}
+
+// Peers returns the gnode nodes currently known to the registry watch.
+func (gn *Gnode) Peers() []registry.NodeInfo {
+	return gn.peers.Snapshot()
+}
+
+// watchRegistry subscribes to the registrar's node add/remove events and
+// keeps gn.peers current for as long as Gnode runs. Backends that don't
+// support Watch (the plain http backend) just leave the peer list empty.
+func (gn *Gnode) watchRegistry() {
+	events, err := gn.registrar.Watch(gn.ctx)
+	if err != nil {
+		gn.logger.Warn("registry watch unsupported by backend, peer list disabled")
+		return
+	}
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			gn.peers.apply(ev)
+		case <-gn.exitChan:
+			return
+		}
+	}
+}
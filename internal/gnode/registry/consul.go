@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/wuzhc/gmq/configs"
+)
+
+type consulRegistrar struct {
+	cli       *api.Client
+	sessionId string
+	key       string
+}
+
+func newConsulRegistrar(cfg *configs.GnodeConfig) (*consulRegistrar, error) {
+	conf := api.DefaultConfig()
+	conf.Address = cfg.RegistryAddrs
+
+	cli, err := api.NewClient(conf)
+	if err != nil {
+		return nil, fmt.Errorf("registry: consul client, %w", err)
+	}
+
+	return &consulRegistrar{cli: cli}, nil
+}
+
+func (r *consulRegistrar) Register(ctx context.Context, node NodeInfo) error {
+	// A prior session still holds r.key, so Acquire below would fail with
+	// ok=false unless that session is destroyed first - this path is hit
+	// every time Reload re-registers after a weight change, not just once
+	// at startup.
+	if r.sessionId != "" {
+		if _, err := r.cli.Session().Destroy(r.sessionId, nil); err != nil {
+			return fmt.Errorf("registry: consul destroy prior session, %w", err)
+		}
+		r.sessionId = ""
+	}
+
+	session, _, err := r.cli.Session().Create(&api.SessionEntry{
+		TTL:      defaultLeaseTTL.String(),
+		Behavior: api.SessionBehaviorDelete,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("registry: consul session create, %w", err)
+	}
+
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	r.sessionId = session
+	r.key = fmt.Sprintf("%s/%d", keyPrefix, node.NodeId)
+
+	ok, _, err := r.cli.KV().Acquire(&api.KVPair{
+		Key:     r.key,
+		Value:   data,
+		Session: r.sessionId,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("registry: consul acquire, %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("registry: consul key %s already held by another session", r.key)
+	}
+
+	return nil
+}
+
+// Renew extends the session TTL acquired by Register.
+func (r *consulRegistrar) Renew(ctx context.Context) error {
+	if r.sessionId == "" {
+		return fmt.Errorf("registry: consul session not acquired yet")
+	}
+	_, _, err := r.cli.Session().Renew(r.sessionId, nil)
+	return err
+}
+
+func (r *consulRegistrar) Unregister(ctx context.Context, node NodeInfo) error {
+	if r.sessionId == "" {
+		return nil
+	}
+	_, err := r.cli.Session().Destroy(r.sessionId, nil)
+	return err
+}
+
+func (r *consulRegistrar) Watch(ctx context.Context) (<-chan Event, error) {
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+
+		var lastIndex uint64
+		seen := make(map[int64]struct{})
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			pairs, meta, err := r.cli.KV().List(keyPrefix, &api.QueryOptions{
+				WaitIndex: lastIndex,
+				Context:   ctx,
+			})
+			if err != nil {
+				return
+			}
+			lastIndex = meta.LastIndex
+
+			current := make(map[int64]struct{}, len(pairs))
+			for _, pair := range pairs {
+				var node NodeInfo
+				if err := json.Unmarshal(pair.Value, &node); err != nil {
+					continue
+				}
+				current[node.NodeId] = struct{}{}
+				ch <- Event{Type: EventAdd, Node: node}
+			}
+
+			// A node's session-tied key expires (SessionBehaviorDelete)
+			// without ever showing up as a distinct consul event, so the
+			// only way to notice it left is diffing this listing against
+			// the last one.
+			for nodeId := range seen {
+				if _, ok := current[nodeId]; !ok {
+					ch <- Event{Type: EventRemove, Node: NodeInfo{NodeId: nodeId}}
+				}
+			}
+			seen = current
+		}
+	}()
+
+	return ch, nil
+}
+
+func (r *consulRegistrar) Close() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wuzhc/gmq/configs"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// keyPrefix is the well-known etcd/Consul namespace every gnode publishes
+// itself under: <prefix>/<node_id>.
+const keyPrefix = "/gmq/gnode"
+
+const defaultLeaseTTL = 10 * time.Second
+
+type etcdRegistrar struct {
+	cli     *clientv3.Client
+	leaseId clientv3.LeaseID
+	key     string
+}
+
+func newEtcdRegistrar(cfg *configs.GnodeConfig) (*etcdRegistrar, error) {
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(cfg.RegistryAddrs, ","),
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("registry: etcd dial failed, %w", err)
+	}
+
+	return &etcdRegistrar{cli: cli}, nil
+}
+
+func (r *etcdRegistrar) Register(ctx context.Context, node NodeInfo) error {
+	lease, err := r.cli.Grant(ctx, int64(defaultLeaseTTL.Seconds()))
+	if err != nil {
+		return fmt.Errorf("registry: etcd grant lease, %w", err)
+	}
+
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+
+	r.key = fmt.Sprintf("%s/%d", keyPrefix, node.NodeId)
+	if _, err := r.cli.Put(ctx, r.key, string(data), clientv3.WithLease(lease.ID)); err != nil {
+		return fmt.Errorf("registry: etcd put, %w", err)
+	}
+
+	r.leaseId = lease.ID
+	return nil
+}
+
+// Renew keeps the lease acquired by Register alive. It is meant to be called
+// periodically by gn.wg so a crashed or partitioned node's entry expires on
+// its own and stale routes don't stick around.
+func (r *etcdRegistrar) Renew(ctx context.Context) error {
+	if r.leaseId == 0 {
+		return fmt.Errorf("registry: etcd lease not acquired yet")
+	}
+	_, err := r.cli.KeepAliveOnce(ctx, r.leaseId)
+	return err
+}
+
+func (r *etcdRegistrar) Unregister(ctx context.Context, node NodeInfo) error {
+	if r.leaseId != 0 {
+		_, err := r.cli.Revoke(ctx, r.leaseId)
+		return err
+	}
+	_, err := r.cli.Delete(ctx, r.key)
+	return err
+}
+
+func (r *etcdRegistrar) Watch(ctx context.Context) (<-chan Event, error) {
+	ch := make(chan Event)
+	wc := r.cli.Watch(ctx, keyPrefix, clientv3.WithPrefix())
+
+	go func() {
+		defer close(ch)
+		for resp := range wc {
+			for _, ev := range resp.Events {
+				var node NodeInfo
+				switch ev.Type {
+				case clientv3.EventTypePut:
+					if err := json.Unmarshal(ev.Kv.Value, &node); err != nil {
+						continue
+					}
+					ch <- Event{Type: EventAdd, Node: node}
+				case clientv3.EventTypeDelete:
+					nodeId, err := nodeIdFromKey(string(ev.Kv.Key))
+					if err != nil {
+						continue
+					}
+					ch <- Event{Type: EventRemove, Node: NodeInfo{NodeId: nodeId}}
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (r *etcdRegistrar) Close() error {
+	return r.cli.Close()
+}
+
+// nodeIdFromKey extracts the trailing node id from a "<keyPrefix>/<id>" key,
+// which is all a delete event's Kv gives us: the value is already gone.
+func nodeIdFromKey(key string) (int64, error) {
+	idx := strings.LastIndex(key, "/")
+	if idx < 0 || idx == len(key)-1 {
+		return 0, fmt.Errorf("registry: malformed key %q", key)
+	}
+	return strconv.ParseInt(key[idx+1:], 10, 64)
+}
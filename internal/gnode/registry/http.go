@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/wuzhc/gmq/configs"
+)
+
+// httpRegistrar preserves the original gregister behaviour: a plain HTTP GET
+// against every configured host, no lease and no Watch support. It exists so
+// `[registry] backend=http` (or the zero value, for existing ini files)
+// keeps working unchanged.
+type httpRegistrar struct {
+	hosts []string
+}
+
+type httpRegisterResp struct {
+	Code int         `json:"code"`
+	Data interface{} `json:"data"`
+	Msg  string      `json:"msg"`
+}
+
+func newHttpRegistrar(cfg *configs.GnodeConfig) *httpRegistrar {
+	return &httpRegistrar{hosts: strings.Split(cfg.GregisterAddr, ",")}
+}
+
+func (r *httpRegistrar) Register(ctx context.Context, node NodeInfo) error {
+	for _, host := range r.hosts {
+		url := fmt.Sprintf("%s/register?tcp_addr=%s&http_addr=%s&weight=%d&node_id=%d", host, node.TcpAddr, node.HttpAddr, node.Weight, node.NodeId)
+		if err := r.call(ctx, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *httpRegistrar) Unregister(ctx context.Context, node NodeInfo) error {
+	for _, host := range r.hosts {
+		url := fmt.Sprintf("%s/unregister?tcp_addr=%s", host, node.TcpAddr)
+		if err := r.call(ctx, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Renew is a no-op: the http backend has no lease to keep alive.
+func (r *httpRegistrar) Renew(ctx context.Context) error {
+	return nil
+}
+
+// Watch is unsupported by the http backend; there is no event stream to
+// subscribe to, only the request/response register/unregister calls above.
+func (r *httpRegistrar) Watch(ctx context.Context) (<-chan Event, error) {
+	return nil, fmt.Errorf("registry: backend %q does not support Watch", "http")
+}
+
+func (r *httpRegistrar) Close() error {
+	return nil
+}
+
+func (r *httpRegistrar) call(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var r2 httpRegisterResp
+	if err := json.Unmarshal(body, &r2); err != nil {
+		return err
+	}
+	if r2.Code == 1 {
+		return fmt.Errorf("registry: %s", r2.Msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+// Package registry abstracts gregister service discovery so Gnode can
+// register itself, keep its registration alive and watch for other nodes
+// coming and going without polling.
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wuzhc/gmq/configs"
+)
+
+// NodeInfo describes a single gnode instance as published to the registry.
+type NodeInfo struct {
+	NodeId   int64  `json:"node_id"`
+	TcpAddr  string `json:"tcp_addr"`
+	HttpAddr string `json:"http_addr"`
+	Weight   int    `json:"weight"`
+}
+
+// EventType distinguishes the two kinds of change a Watch can observe.
+type EventType int
+
+const (
+	EventAdd EventType = iota
+	EventRemove
+)
+
+// Event is emitted on the channel returned by Registrar.Watch.
+type Event struct {
+	Type EventType
+	Node NodeInfo
+}
+
+// Registrar is implemented by every service discovery backend. Register
+// publishes the local node and acquires a lease/session that Renew keeps
+// alive; Unregister revokes it. Watch streams add/remove events for every
+// node under the registry prefix, including the caller's own.
+type Registrar interface {
+	Register(ctx context.Context, node NodeInfo) error
+	Renew(ctx context.Context) error
+	Unregister(ctx context.Context, node NodeInfo) error
+	Watch(ctx context.Context) (<-chan Event, error)
+	Close() error
+}
+
+// New builds the Registrar selected by cfg.RegistryBackend.
+func New(cfg *configs.GnodeConfig) (Registrar, error) {
+	switch cfg.RegistryBackend {
+	case "", "http":
+		return newHttpRegistrar(cfg), nil
+	case "etcd":
+		return newEtcdRegistrar(cfg)
+	case "consul":
+		return newConsulRegistrar(cfg)
+	default:
+		return nil, fmt.Errorf("registry: unknown backend %q", cfg.RegistryBackend)
+	}
+}
@@ -0,0 +1,98 @@
+package gnode
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultShutdownTimeout is used when [node] shutdown_timeout is unset or 0.
+const defaultShutdownTimeout = 10 * time.Second
+
+// unregisterRetries bounds how many times Shutdown retries the upstream
+// unregister call before giving up and draining anyway.
+const unregisterRetries = 3
+
+func (gn *Gnode) shutdownTimeout() time.Duration {
+	if cfg := gn.Config(); cfg != nil && cfg.ShutdownTimeout > 0 {
+		return time.Duration(cfg.ShutdownTimeout) * time.Second
+	}
+	return defaultShutdownTimeout
+}
+
+// Exit is the convenience wrapper around Shutdown, kept for callers that
+// don't need control over the drain deadline.
+func (gn *Gnode) Exit() {
+	ctx, cancel := context.WithTimeout(context.Background(), gn.shutdownTimeout())
+	defer cancel()
+
+	if err := gn.Shutdown(ctx); err != nil {
+		log.Println("Shutdown failed, ", err)
+	}
+}
+
+// Shutdown stops Gnode in an order that avoids killing in-flight work:
+//  1. unregister from gregister first (with retries) so upstream stops
+//     routing new traffic to this node before anything else happens;
+//  2. let the tcp/http servers drain their existing connections, bounded by
+//     ctx's deadline;
+//  3. stop the dispatcher and close the Redis client so pending writes have
+//     a chance to flush;
+//  4. close exitChan and cancel gn.ctx last - exitChan is what
+//     renewRegistration/watchConfig/watchRegistry select on to stop, and
+//     cancelling gn.ctx aborts any call still running under it, such as the
+//     registry Watch stream. The Redis client is already closed by then.
+func (gn *Gnode) Shutdown(ctx context.Context) error {
+	var firstErr error
+	record := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	record(gn.unregisterWithRetry(ctx))
+
+	if gn.httpServ != nil {
+		record(gn.httpServ.Stop(ctx))
+	}
+	if gn.tcpServ != nil {
+		record(gn.tcpServ.Stop(ctx))
+	}
+	if gn.dispatcher != nil {
+		record(gn.dispatcher.Stop(ctx))
+	}
+	if gn.redisDB != nil {
+		record(gn.redisDB.Close())
+	}
+	if gn.registrar != nil {
+		record(gn.registrar.Close())
+	}
+	if gn.tracerShutdown != nil {
+		record(gn.tracerShutdown(ctx))
+	}
+
+	close(gn.exitChan)
+	gn.cancel()
+	return firstErr
+}
+
+// unregisterWithRetry gives gregister a few chances to accept the
+// unregister call before the drain deadline runs out, since a single
+// transient failure here would otherwise leave the node routable during its
+// own shutdown.
+func (gn *Gnode) unregisterWithRetry(ctx context.Context) error {
+	var err error
+	for attempt := 1; attempt <= unregisterRetries; attempt++ {
+		if err = gn.registrar.Unregister(ctx, gn.nodeInfo()); err == nil {
+			return nil
+		}
+
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return fmt.Errorf("unregister: %w (gave up after %d attempt(s): %v)", ctx.Err(), attempt, err)
+		}
+	}
+	return fmt.Errorf("unregister: giving up after %d attempts, %w", unregisterRetries, err)
+}
@@ -0,0 +1,143 @@
+package gnode
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/wuzhc/gmq/pkg/tracing"
+)
+
+// TcpServ accepts producer/consumer connections over gmq's TCP protocol.
+type TcpServ struct {
+	ctx      *Context
+	listener net.Listener
+	wg       sync.WaitGroup // one per in-flight handle() goroutine, so Stop can wait for them to drain
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func NewTcpServ(ctx *Context) *TcpServ {
+	return &TcpServ{ctx: ctx, conns: make(map[net.Conn]struct{})}
+}
+
+// Run listens and accepts connections until Stop closes the listener, at
+// which point Accept returns an error and Run returns.
+func (t *TcpServ) Run() {
+	ln, err := t.listen()
+	if err != nil {
+		t.ctx.Logger.Error("tcp serv: listen", err)
+		return
+	}
+	t.listener = ln
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.track(conn)
+		t.wg.Add(1)
+		go t.handle(conn)
+	}
+}
+
+func (t *TcpServ) listen() (net.Listener, error) {
+	cfg := t.ctx.Conf
+	if !cfg.TcpServEnableTls {
+		return net.Listen("tcp", cfg.TcpServAddr)
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TcpServCertFile, cfg.TcpServKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tcp serv: load tls cert, %w", err)
+	}
+	return tls.Listen("tcp", cfg.TcpServAddr, &tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+func (t *TcpServ) track(conn net.Conn) {
+	t.mu.Lock()
+	t.conns[conn] = struct{}{}
+	t.mu.Unlock()
+	if t.ctx.Metrics != nil {
+		t.ctx.Metrics.TcpConnections.Inc()
+	}
+}
+
+func (t *TcpServ) untrack(conn net.Conn) {
+	t.mu.Lock()
+	delete(t.conns, conn)
+	t.mu.Unlock()
+	if t.ctx.Metrics != nil {
+		t.ctx.Metrics.TcpConnections.Dec()
+	}
+}
+
+// handle reads the optional "trace:k=v;k=v\n" header frame gmq's TCP
+// protocol sends ahead of the command frame, extracts it via
+// tcpHeaderCarrier and starts a span so a produce spanning tcp -> bucket ->
+// deliver stitches into one trace.
+func (t *TcpServ) handle(conn net.Conn) {
+	defer t.wg.Done()
+	defer t.untrack(conn)
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	carrier := make(tcpHeaderCarrier)
+	if line, err := r.ReadString('\n'); err == nil && strings.HasPrefix(line, "trace:") {
+		for _, kv := range strings.Split(strings.TrimPrefix(strings.TrimSpace(line), "trace:"), ";") {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				carrier.Set(k, v)
+			}
+		}
+	}
+
+	spanCtx := otel.GetTextMapPropagator().Extract(t.ctx.Gnode.ctx, carrier)
+	_, span := tracing.Tracer().Start(spanCtx, "tcp.handle")
+	defer span.End()
+
+	// TODO: dispatch the remaining frames to the producer/consumer protocol
+	// handlers once they land; for now the connection is drained until the
+	// peer or Stop closes it.
+	io.Copy(io.Discard, r)
+}
+
+// Stop closes the listener so Accept unblocks and no new connections are
+// taken, then gives in-flight handle() goroutines until ctx's deadline to
+// finish on their own before force-closing whatever's left - mirroring
+// HttpServ.Stop's server.Shutdown(ctx) drain contract instead of severing
+// every connection immediately.
+func (t *TcpServ) Stop(ctx context.Context) error {
+	if t.listener != nil {
+		if err := t.listener.Close(); err != nil {
+			return fmt.Errorf("tcp serv: close listener, %w", err)
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		for conn := range t.conns {
+			conn.Close()
+		}
+		t.mu.Unlock()
+
+		<-drained
+		return fmt.Errorf("tcp serv: %w before all connections drained", ctx.Err())
+	}
+}
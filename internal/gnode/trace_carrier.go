@@ -0,0 +1,28 @@
+package gnode
+
+import "go.opentelemetry.io/otel/propagation"
+
+// tcpHeaderCarrier lets NewTcpServ propagate trace context over the gmq TCP
+// protocol: an optional "trace:key=value;key=value" header frame sent ahead
+// of the command frame, mirroring how HTTP carries W3C traceparent. It implements
+// propagation.TextMapCarrier so it works with otel.GetTextMapPropagator()
+// directly.
+type tcpHeaderCarrier map[string]string
+
+func (c tcpHeaderCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c tcpHeaderCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c tcpHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = tcpHeaderCarrier{}
@@ -0,0 +1,152 @@
+// Package logger provides the structured, leveled logger used across gmq,
+// built on top of zerolog. It replaces the older pkg/logs target-based
+// dispatcher: With derives a child logger carrying an extra field such as a
+// subsystem name, and every line carries a node_id plus whatever fields the
+// caller attaches. The gnode subsystems all log through the one root logger
+// stored on Context; none of them is handed a child logger.
+package logger
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Config mirrors the `[log]` ini section that selects format, sampling and
+// destination.
+type Config struct {
+	NodeId     int64
+	Filename   string
+	Level      int
+	Rotate     bool   // rotate Filename via lumberjack once it exceeds MaxSize
+	MaxSize    int    // megabytes; only meaningful when Rotate is set
+	Format     string // "json" (default) or "console"
+	Sampling   int    // log 1 in every N messages, counted across all call sites; 0 disables sampling
+	TargetType string // "file", "console" or "file,console"
+}
+
+// Logger wraps a zerolog.Logger so call sites don't depend on zerolog
+// directly and so With() returns the same type gmq code already expects.
+// zl is guarded by mu so Reconfigure can swap it in place while other
+// goroutines are logging through the same *Logger - in particular the one
+// stored on Context, which every subsystem holds for the life of the
+// process.
+type Logger struct {
+	mu sync.RWMutex
+	zl zerolog.Logger
+}
+
+// New builds the root logger for a Gnode instance from cfg.
+func New(cfg Config) (*Logger, error) {
+	zl, err := build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{zl: zl}, nil
+}
+
+// Reconfigure rebuilds the underlying zerolog.Logger from cfg and swaps it
+// in place, so every holder of this *Logger (in particular ctx.Logger,
+// captured once by each subsystem at startup) picks up the change on its
+// next log call without needing a new Logger handed to it.
+func (l *Logger) Reconfigure(cfg Config) error {
+	zl, err := build(cfg)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.zl = zl
+	l.mu.Unlock()
+	return nil
+}
+
+func build(cfg Config) (zerolog.Logger, error) {
+	var writers []io.Writer
+	for _, t := range strings.Split(cfg.TargetType, ",") {
+		switch strings.TrimSpace(t) {
+		case "console":
+			writers = append(writers, consoleWriter(cfg.Format))
+		case "file":
+			w, err := fileWriter(cfg)
+			if err != nil {
+				return zerolog.Logger{}, err
+			}
+			writers = append(writers, w)
+		}
+	}
+	if len(writers) == 0 {
+		writers = append(writers, consoleWriter(cfg.Format))
+	}
+
+	zl := zerolog.New(io.MultiWriter(writers...)).
+		Level(zerolog.Level(cfg.Level)).
+		With().
+		Timestamp().
+		Int64("node_id", cfg.NodeId).
+		Logger()
+
+	if cfg.Sampling > 0 {
+		zl = zl.Sample(&zerolog.BasicSampler{N: uint32(cfg.Sampling)})
+	}
+
+	return zl, nil
+}
+
+func consoleWriter(format string) io.Writer {
+	if format == "console" {
+		return zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	}
+	return os.Stdout
+}
+
+// fileWriter rotates Filename via lumberjack once it exceeds MaxSize
+// megabytes when Rotate is set, preserving the rotate/max_size behaviour the
+// old logs.Dispatcher had. Without Rotate it appends to Filename directly,
+// since lumberjack would still rotate at its own 100 MB default.
+func fileWriter(cfg Config) (io.Writer, error) {
+	if !cfg.Rotate {
+		return os.OpenFile(cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	return &lumberjack.Logger{
+		Filename: cfg.Filename,
+		MaxSize:  cfg.MaxSize,
+	}, nil
+}
+
+// With returns a child logger carrying an additional string field, e.g.
+// ctx.Logger.With("subsystem", "tcp").
+func (l *Logger) With(key, value string) *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return &Logger{zl: l.zl.With().Str(key, value).Logger()}
+}
+
+func (l *Logger) Debug(msg string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	l.zl.Debug().Msg(msg)
+}
+
+func (l *Logger) Info(msg string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	l.zl.Info().Msg(msg)
+}
+
+func (l *Logger) Warn(msg string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	l.zl.Warn().Msg(msg)
+}
+
+func (l *Logger) Error(msg string, err error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	l.zl.Error().Err(err).Msg(msg)
+}
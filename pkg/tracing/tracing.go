@@ -0,0 +1,73 @@
+// Package tracing wires gmq into OpenTelemetry. It is a thin setup layer:
+// call Init once at startup to install the provider and get a shutdown func,
+// and use Tracer() to span a produce -> bucket -> deliver flow with msg_id as an
+// attribute. Disabled by default so it costs nothing on the common path.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/wuzhc/gmq/gnode"
+
+// Config mirrors the `[tracing]` ini section.
+type Config struct {
+	Enabled     bool
+	Endpoint    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Shutdown flushes and tears down the tracer provider installed by Init.
+type Shutdown func(context.Context) error
+
+// noopShutdown is returned when tracing is disabled so callers can always
+// defer the returned Shutdown unconditionally.
+func noopShutdown(context.Context) error { return nil }
+
+// Init installs a global TracerProvider exporting spans to cfg.Endpoint over
+// OTLP/gRPC. When cfg.Enabled is false it installs nothing and Tracer()
+// falls back to OpenTelemetry's no-op tracer.
+func Init(ctx context.Context, cfg Config) (Shutdown, error) {
+	if !cfg.Enabled {
+		return noopShutdown, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("tracing: build otlp exporter, %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceNameKey.String(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: build resource, %w", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.TraceIDRatioBased(ratio)),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the gmq tracer. Safe to call whether or not Init installed
+// a real provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}